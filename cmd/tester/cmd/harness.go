@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/b-harvest/modules-test-tool/config"
+	"github.com/b-harvest/modules-test-tool/harness"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// HarnessConfigFile is the on-disk description of the chains and relayer a
+// `tester harness up` run should bring up locally.
+type HarnessConfigFile struct {
+	RelayerImage string                `yaml:"relayerImage"`
+	RelayerHome  string                `yaml:"relayerHome"`
+	Chains       []harness.ChainConfig `yaml:"chains"`
+}
+
+func HarnessCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "harness",
+		Short: "Manage an ephemeral, local multi-chain IBC harness",
+	}
+	cmd.AddCommand(harnessUpCmd())
+	return cmd
+}
+
+func harnessUpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "up [harness-file] [scenario-file]",
+		Short: "Start a local multi-chain IBC harness and run a scenario against it",
+		Args:  cobra.ExactArgs(2),
+		Long: `Spin up local chain and relayer containers described by harness-file,
+wire the resulting RPC/gRPC endpoints into config.IBCconfig, then run the
+scenario described by scenario-file against them. The harness is torn down
+on exit, including on error. Ctrl-C while the harness is starting aborts the
+startup and tears it down; once the scenario is running, Ctrl-C does not stop
+it, and the teardown follows when the scenario finishes.
+
+Example: $tester harness up ./harness/local.yaml ./scenarios/basic-transfer.yaml
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := SetLogger(logLevel)
+			if err != nil {
+				return err
+			}
+
+			raw, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read harness file: %s", err)
+			}
+			var harnessFile HarnessConfigFile
+			if err := yaml.Unmarshal(raw, &harnessFile); err != nil {
+				return fmt.Errorf("failed to parse harness file: %s", err)
+			}
+
+			cfg, err := config.Read(config.DefaultConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to read config file: %s", err)
+			}
+
+			h := harness.New(harness.Config{
+				Chains:       harnessFile.Chains,
+				RelayerImage: harnessFile.RelayerImage,
+				RelayerHome:  harnessFile.RelayerHome,
+				Mnemonics:    cfg.Custom.Mnemonics,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, os.Interrupt)
+			go func() {
+				<-sigCh
+				log.Warn().Msg("received interrupt, tearing down harness")
+				cancel()
+			}()
+			defer func() {
+				if err := h.Down(context.Background()); err != nil {
+					log.Error().Err(err).Msg("failed to tear down harness")
+				}
+			}()
+
+			chains, err := h.Up(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to start harness: %s", err)
+			}
+			cfg.IBCconfig.Chains = chains
+			log.Info().Int("chains", len(chains)).Msg("harness is up")
+
+			scenario, err := loadScenarioFile(args[1])
+			if err != nil {
+				return err
+			}
+
+			return runScenarioFile(cmd, cfg, scenario, "")
+		},
+	}
+	return cmd
+}
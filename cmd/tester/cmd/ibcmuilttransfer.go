@@ -11,8 +11,11 @@ import (
 	"github.com/b-harvest/modules-test-tool/client"
 	"github.com/b-harvest/modules-test-tool/client/grpc"
 	"github.com/b-harvest/modules-test-tool/config"
+	"github.com/b-harvest/modules-test-tool/metrics"
 
+	"github.com/b-harvest/modules-test-tool/relayer"
 	"github.com/b-harvest/modules-test-tool/tx"
+	coretx "github.com/b-harvest/modules-test-tool/tx"
 	"github.com/b-harvest/modules-test-tool/wallet"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 
@@ -25,6 +28,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	flagEnsureChannel   = "ensure-channel"
+	flagChannelVersion  = "channel-version"
+	flagChannelOrder    = "channel-order"
+	flagOverrideChannel = "override-channel"
+	flagRelayerHome     = "relayer-home"
+	flagRelayerBinary   = "relayer-binary"
+	flagTrackPackets    = "track-packets"
+)
+
 func IBCMuiltTransferCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "muilt-transfer [src-chains] [dst-chains] [amount] [blocks] [tx-num] [msg-num]",
@@ -52,6 +65,15 @@ msg-num: how many transaction messages to be included in a transaction
 				return err
 			}
 
+			metricsAddr, err := cmd.Flags().GetString(flagMetricsAddr)
+			if err != nil {
+				return err
+			}
+			if metricsAddr != "" {
+				metricsServer := metrics.StartServer(metricsAddr)
+				defer metrics.Shutdown(context.Background(), metricsServer)
+			}
+
 			cfg, err := config.Read(config.DefaultConfigPath)
 			if err != nil {
 				return fmt.Errorf("failed to read config file: %s", err)
@@ -106,6 +128,16 @@ msg-num: how many transaction messages to be included in a transaction
 	cmd.Flags().String(flagPacketTimeoutHeight, ibctypes.DefaultRelativePacketTimeoutHeight, "Packet timeout block height. The timeout is disabled when set to 0-0.")
 	cmd.Flags().Uint64(flagPacketTimeoutTimestamp, ibctypes.DefaultRelativePacketTimeoutTimestamp, "Packet timeout timestamp in nanoseconds. Default is 10 minutes. The timeout is disabled when set to 0.")
 	cmd.Flags().Bool(flagAbsoluteTimeouts, false, "Timeout flags are used as absolute timeouts.")
+	cmd.Flags().Bool(flagEnsureChannel, false, "Drive the configured relayer to create client/connection/channel before load starts")
+	cmd.Flags().String(flagChannelVersion, "ics20-1", "Channel version to request when --ensure-channel is set")
+	cmd.Flags().String(flagChannelOrder, "unordered", "Channel order to request when --ensure-channel is set")
+	cmd.Flags().Bool(flagOverrideChannel, false, "Pass --override to the relayer so a fresh channel is created for this run")
+	cmd.Flags().String(flagRelayerHome, "", "Home directory of the relayer invoked by --ensure-channel")
+	cmd.Flags().String(flagRelayerBinary, "rly", "Relayer binary to invoke for --ensure-channel, e.g. rly or hermes")
+	cmd.Flags().Int(flagWorkers, 1, "Number of concurrent signer/broadcaster workers per dst chain. >1 enables the parallel broadcast pipeline.")
+	cmd.Flags().Int(flagInflight, 0, "Depth of the in-flight job queue when --workers > 1, defaults to --workers.")
+	cmd.Flags().String(flagMetricsAddr, "", "Address to expose Prometheus metrics on, e.g. :9090. Disabled when empty.")
+	cmd.Flags().Bool(flagTrackPackets, false, "Track send/acknowledge_packet events per src/dst chain pair and observe ibc_packet_ack_latency_seconds.")
 	flags.AddTxFlagsToCmd(cmd)
 	return cmd
 }
@@ -139,6 +171,19 @@ func SrcChainsend(ctx context.Context, cmd *cobra.Command, cfg *config.Config, d
 
 	defer MainChainClient.Stop() // nolint: errcheck
 	defer MainChainClient.GRPC.Close()
+
+	ensureChannel, err := cmd.Flags().GetBool(flagEnsureChannel)
+	if err != nil {
+		return err
+	}
+	var ensuredChannels map[string]ensuredChannel
+	if ensureChannel {
+		ensuredChannels, err = ensureChannelsForSubchains(ctx, cmd, mainchain, subchains)
+		if err != nil {
+			return err
+		}
+	}
+
 	grpcclient := MainChainClient.GRPC
 	mainchainibcinfo, err := grpcclient.AllChainsTrace(ctx)
 	if err != nil {
@@ -149,29 +194,49 @@ func SrcChainsend(ctx context.Context, cmd *cobra.Command, cfg *config.Config, d
 		wait.Add(1)
 		go func(index int, dstchaininfo config.IBCchain) {
 			defer wait.Done()
-			DstChainsend(ctx, cmd, MainChainClient, index, dstchaininfo, mainchainibcinfo, mainchain, cfg, args)
+			DstChainsend(ctx, cmd, MainChainClient, index, dstchaininfo, mainchainibcinfo, ensuredChannels, mainchain, cfg, args)
 		}(index, dstchaininfo)
 	}
 	wait.Wait()
 	return nil
 }
 
-func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *client.Client, accountindex int, dstchaininfo config.IBCchain, mainchainibcinfo []grpc.OpenChannel, mainchain config.IBCchain, cfg *config.Config, args []string) error {
+func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *client.Client, accountindex int, dstchaininfo config.IBCchain, mainchainibcinfo []grpc.OpenChannel, ensuredChannels map[string]ensuredChannel, mainchain config.IBCchain, cfg *config.Config, args []string) error {
 	ibcclientCtx := MainChainClient.GetCLIContext()
 	chainID, err := MainChainClient.RPC.GetNetworkChainID(ctx)
 	if err != nil {
 		return err
 	}
+
+	trackPackets, err := cmd.Flags().GetBool(flagTrackPackets)
+	if err != nil {
+		return err
+	}
+	if trackPackets {
+		tracker, err := metrics.NewPacketTracker(mainchain.ChainId, dstchaininfo.ChainId, mainchain.Rpc, dstchaininfo.Rpc)
+		if err != nil {
+			return fmt.Errorf("failed to start packet tracker: %s", err)
+		}
+		go func() {
+			if err := tracker.Run(ctx); err != nil {
+				log.Error().Err(err).Str("dstchain", dstchaininfo.ChainId).Msg("packet tracker stopped")
+			}
+		}()
+	}
+
 	var srcPort string
 	var srcChannel string
-	var receiver string
-	for _, i := range mainchainibcinfo {
-		if dstchaininfo.ChainId == i.ClientChainId {
-			srcPort = "transfer"
-			srcChannel = i.ChannelId
-			receiver = dstchaininfo.DstAddress
-
-			break
+	receiver := dstchaininfo.DstAddress
+	if ensured, ok := ensuredChannels[dstchaininfo.ChainId]; ok {
+		srcPort = ensured.port
+		srcChannel = ensured.channel
+	} else {
+		for _, i := range mainchainibcinfo {
+			if dstchaininfo.ChainId == i.ClientChainId {
+				srcPort = "transfer"
+				srcChannel = i.ChannelId
+				break
+			}
 		}
 	}
 	sendcoin := args[2] + mainchain.TokenDenom
@@ -227,6 +292,49 @@ func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *clie
 	log.Info().Msgf("starting simulation #%d, blocks = %d, num txs per block = %d <%s>", blocks+1, blocks, txNum, mainchain.ChainId)
 	targetHeight := startingHeight
 
+	workers, err := cmd.Flags().GetInt(flagWorkers)
+	if err != nil {
+		return err
+	}
+	inflight, err := cmd.Flags().GetInt(flagInflight)
+	if err != nil {
+		return err
+	}
+
+	var broadcaster *coretx.Broadcaster
+	if workers > 1 {
+		broadcaster = coretx.NewBroadcaster(workers, inflight, accSeq, accNum,
+			func(ctx context.Context, seq, num uint64, msgs ...sdktypes.Msg) ([]byte, error) {
+				return tx.IbcSign(ctx, seq, num, privKey, msgs...)
+			},
+			func(ctx context.Context, txByte []byte) (*sdktypes.TxResponse, error) {
+				resp, err := MainChainClient.GRPC.BroadcastTx(ctx, txByte)
+				if err != nil {
+					return nil, err
+				}
+				switch {
+				case resp.TxResponse.Code == 0x14:
+					log.Warn().Str("dstchain", dstchaininfo.ChainId).Msg("mempool is full, pausing until next block")
+					metrics.MempoolFullTotal.WithLabelValues(mainchain.ChainId).Inc()
+					metrics.TxBroadcastTotal.WithLabelValues(mainchain.ChainId, "mempool_full").Inc()
+				case resp.TxResponse.Code != 0:
+					metrics.TxBroadcastTotal.WithLabelValues(mainchain.ChainId, "failed").Inc()
+				default:
+					metrics.TxBroadcastTotal.WithLabelValues(mainchain.ChainId, "success").Inc()
+				}
+				return resp.TxResponse, nil
+			},
+			func(ctx context.Context) (uint64, uint64, error) {
+				account, err := MainChainClient.GRPC.GetBaseAccountInfo(ctx, accAddr)
+				if err != nil {
+					return 0, 0, err
+				}
+				return account.GetSequence(), account.GetAccountNumber(), nil
+			},
+		)
+	}
+
+	var prevBroadcasterSent int
 	for i := 0; i < blocks; i++ {
 		st, err := MainChainClient.RPC.Status(ctx)
 		if err != nil {
@@ -239,31 +347,50 @@ func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *clie
 
 		//started := time.Now()
 		sent := 0
-	loop:
-		for sent < txNum {
-			msgs, err := tx.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, receiver, msgNum)
-			if err != nil {
-				return fmt.Errorf("failed to create msg: %s", err)
-			}
-			for sent < txNum {
-				txByte, err := tx.IbcSign(ctx, accSeq, accNum, privKey, msgs...)
+		if broadcaster != nil {
+			submitted := 0
+			for submitted < txNum {
+				msgs, err := tx.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, receiver, msgNum)
 				if err != nil {
-					return fmt.Errorf("failed to sign and broadcast: %s", err)
+					return fmt.Errorf("failed to create msg: %s", err)
 				}
-				resp, err := MainChainClient.GRPC.BroadcastTx(ctx, txByte)
-				//log.Info().Msgf("took %s broadcasting txs", resp)
+				if err := broadcaster.Submit(ctx, msgs...); err != nil {
+					return fmt.Errorf("failed to submit batch: %s", err)
+				}
+				submitted++
+			}
+		} else {
+		loop:
+			for sent < txNum {
+				msgs, err := tx.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, receiver, msgNum)
 				if err != nil {
-					return fmt.Errorf("broadcast tx: %w", err)
+					return fmt.Errorf("failed to create msg: %s", err)
 				}
-				accSeq = accSeq + 1
-				if resp.TxResponse.Code != 0 {
-					if resp.TxResponse.Code == 0x14 {
-						log.Warn().Msg("mempool is full, stopping")
-						accSeq = accSeq - 1
-						break loop
+				for sent < txNum {
+					txByte, err := tx.IbcSign(ctx, accSeq, accNum, privKey, msgs...)
+					if err != nil {
+						return fmt.Errorf("failed to sign and broadcast: %s", err)
+					}
+					resp, err := MainChainClient.GRPC.BroadcastTx(ctx, txByte)
+					//log.Info().Msgf("took %s broadcasting txs", resp)
+					if err != nil {
+						return fmt.Errorf("broadcast tx: %w", err)
+					}
+					accSeq = accSeq + 1
+					if resp.TxResponse.Code != 0 {
+						if resp.TxResponse.Code == 0x14 {
+							log.Warn().Msg("mempool is full, stopping")
+							metrics.MempoolFullTotal.WithLabelValues(mainchain.ChainId).Inc()
+							metrics.TxBroadcastTotal.WithLabelValues(mainchain.ChainId, "mempool_full").Inc()
+							accSeq = accSeq - 1
+							break loop
+						}
+						metrics.TxBroadcastTotal.WithLabelValues(mainchain.ChainId, "failed").Inc()
+					} else {
+						metrics.TxBroadcastTotal.WithLabelValues(mainchain.ChainId, "success").Inc()
 					}
+					sent++
 				}
-				sent++
 			}
 		}
 		//log.Debug().Msgf("took %s broadcasting txs", time.Since(started))
@@ -271,6 +398,15 @@ func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *clie
 		if err := rpcclient.WaitForHeight(MainChainClient.RPC, targetHeight, nil); err != nil {
 			return fmt.Errorf("wait for height: %w", err)
 		}
+		if broadcaster != nil {
+			broadcaster.NextBlock()
+			if err := broadcaster.Flush(ctx); err != nil {
+				return fmt.Errorf("flush broadcaster: %w", err)
+			}
+			totalSent, _ := broadcaster.Counts()
+			sent = totalSent - prevBroadcasterSent
+			prevBroadcasterSent = totalSent
+		}
 		r, err := MainChainClient.RPC.Block(ctx, &targetHeight)
 		if err != nil {
 			return err
@@ -282,8 +418,10 @@ func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *clie
 		} else {
 			blockDuration = r.Block.Time.Sub(bt)
 			delete(blockTimes, targetHeight-1)
+			metrics.BlockDurationSeconds.WithLabelValues(mainchain.ChainId).Observe(blockDuration.Seconds())
 		}
 		blockTimes[targetHeight] = r.Block.Time
+		metrics.TxCommittedTotal.WithLabelValues(mainchain.ChainId, dstchaininfo.ChainId).Add(float64(len(r.Block.Txs)))
 		log.Info().
 			Int64("height", targetHeight).
 			Str("srcchain", mainchain.ChainId).
@@ -295,5 +433,64 @@ func DstChainsend(ctx context.Context, cmd *cobra.Command, MainChainClient *clie
 			Msg("block committed")
 		targetHeight++
 	}
+
+	if broadcaster != nil {
+		sent, failed := broadcaster.Close()
+		log.Info().Int("sent", sent).Int("failed", failed).Str("dstchain", dstchaininfo.ChainId).Msg("broadcaster drained")
+	}
+
 	return nil
 }
+
+// ensuredChannel is the relayer-confirmed src port/channel for one dst
+// chain, fed into DstChainsend instead of whatever the node itself reports.
+type ensuredChannel struct {
+	port    string
+	channel string
+}
+
+// ensureChannelsForSubchains drives the configured relayer to create a
+// client/connection/channel between mainchain and each of subchains, waiting
+// for channel_open_confirm on each before returning the resulting src
+// port/channel per dst chain id.
+func ensureChannelsForSubchains(ctx context.Context, cmd *cobra.Command, mainchain config.IBCchain, subchains []config.IBCchain) (map[string]ensuredChannel, error) {
+	binary, err := cmd.Flags().GetString(flagRelayerBinary)
+	if err != nil {
+		return nil, err
+	}
+	home, err := cmd.Flags().GetString(flagRelayerHome)
+	if err != nil {
+		return nil, err
+	}
+	version, err := cmd.Flags().GetString(flagChannelVersion)
+	if err != nil {
+		return nil, err
+	}
+	order, err := cmd.Flags().GetString(flagChannelOrder)
+	if err != nil {
+		return nil, err
+	}
+	override, err := cmd.Flags().GetBool(flagOverrideChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := relayer.CreateChannelOptions{
+		SourcePortName: "transfer",
+		DestPortName:   "transfer",
+		Order:          order,
+		Version:        version,
+		Override:       override,
+	}
+	r := relayer.NewRelayer(binary, home)
+	channels := make(map[string]ensuredChannel, len(subchains))
+	for _, sub := range subchains {
+		path := fmt.Sprintf("%s-%s", mainchain.ChainId, sub.ChainId)
+		port, channel, err := r.EnsureChannel(ctx, path, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to ensure channel for path %q: %s", path, err)
+		}
+		channels[sub.ChainId] = ensuredChannel{port: port, channel: channel}
+	}
+	return channels, nil
+}
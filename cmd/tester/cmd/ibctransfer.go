@@ -9,7 +9,9 @@ import (
 
 	"github.com/b-harvest/modules-test-tool/client"
 	"github.com/b-harvest/modules-test-tool/config"
+	"github.com/b-harvest/modules-test-tool/metrics"
 	"github.com/b-harvest/modules-test-tool/tx"
+	coretx "github.com/b-harvest/modules-test-tool/tx"
 	"github.com/b-harvest/modules-test-tool/wallet"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 
@@ -26,6 +28,9 @@ const (
 	flagPacketTimeoutHeight    = "packet-timeout-height"
 	flagPacketTimeoutTimestamp = "packet-timeout-timestamp"
 	flagAbsoluteTimeouts       = "absolute-timeouts"
+	flagWorkers                = "workers"
+	flagInflight               = "inflight"
+	flagMetricsAddr            = "metrics-addr"
 )
 
 func IBCtransferCmd() *cobra.Command {
@@ -48,6 +53,15 @@ msg-num: how many transaction messages to be included in a transaction
 				return err
 			}
 
+			metricsAddr, err := cmd.Flags().GetString(flagMetricsAddr)
+			if err != nil {
+				return err
+			}
+			if metricsAddr != "" {
+				metricsServer := metrics.StartServer(metricsAddr)
+				defer metrics.Shutdown(context.Background(), metricsServer)
+			}
+
 			cfg, err := config.Read(config.DefaultConfigPath)
 			if err != nil {
 				return fmt.Errorf("failed to read config file: %s", err)
@@ -135,6 +149,49 @@ msg-num: how many transaction messages to be included in a transaction
 			log.Info().Msgf("starting simulation #%d, blocks = %d, num txs per block = %d", blocks+1, blocks, txNum)
 			targetHeight := startingHeight
 
+			workers, err := cmd.Flags().GetInt(flagWorkers)
+			if err != nil {
+				return err
+			}
+			inflight, err := cmd.Flags().GetInt(flagInflight)
+			if err != nil {
+				return err
+			}
+
+			var broadcaster *coretx.Broadcaster
+			if workers > 1 {
+				broadcaster = coretx.NewBroadcaster(workers, inflight, accSeq, accNum,
+					func(ctx context.Context, seq, num uint64, msgs ...sdktypes.Msg) ([]byte, error) {
+						return tx.IbcSign(ctx, seq, num, privKey, msgs...)
+					},
+					func(ctx context.Context, txByte []byte) (*sdktypes.TxResponse, error) {
+						resp, err := client.GRPC.BroadcastTx(ctx, txByte)
+						if err != nil {
+							return nil, err
+						}
+						switch {
+						case resp.TxResponse.Code == 0x14:
+							log.Warn().Msg("mempool is full, pausing until next block")
+							metrics.MempoolFullTotal.WithLabelValues(chainID).Inc()
+							metrics.TxBroadcastTotal.WithLabelValues(chainID, "mempool_full").Inc()
+						case resp.TxResponse.Code != 0:
+							metrics.TxBroadcastTotal.WithLabelValues(chainID, "failed").Inc()
+						default:
+							metrics.TxBroadcastTotal.WithLabelValues(chainID, "success").Inc()
+						}
+						return resp.TxResponse, nil
+					},
+					func(ctx context.Context) (uint64, uint64, error) {
+						account, err := client.GRPC.GetBaseAccountInfo(ctx, accAddr)
+						if err != nil {
+							return 0, 0, err
+						}
+						return account.GetSequence(), account.GetAccountNumber(), nil
+					},
+				)
+			}
+
+			var prevBroadcasterSent int
 			for i := 0; i < blocks; i++ {
 				st, err := client.RPC.Status(ctx)
 				if err != nil {
@@ -147,31 +204,50 @@ msg-num: how many transaction messages to be included in a transaction
 
 				started := time.Now()
 				sent := 0
-			loop:
-				for sent < txNum {
-					msgs, err := tx.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, receiver, msgNum)
-					if err != nil {
-						return fmt.Errorf("failed to create msg: %s", err)
-					}
-					for sent < txNum {
-						txByte, err := tx.IbcSign(ctx, accSeq, accNum, privKey, msgs...)
+				if broadcaster != nil {
+					submitted := 0
+					for submitted < txNum {
+						msgs, err := tx.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, receiver, msgNum)
 						if err != nil {
-							return fmt.Errorf("failed to sign and broadcast: %s", err)
+							return fmt.Errorf("failed to create msg: %s", err)
 						}
-						resp, err := client.GRPC.BroadcastTx(ctx, txByte)
-						//log.Info().Msgf("took %s broadcasting txs", resp)
+						if err := broadcaster.Submit(ctx, msgs...); err != nil {
+							return fmt.Errorf("failed to submit batch: %s", err)
+						}
+						submitted++
+					}
+				} else {
+				loop:
+					for sent < txNum {
+						msgs, err := tx.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, receiver, msgNum)
 						if err != nil {
-							return fmt.Errorf("broadcast tx: %w", err)
+							return fmt.Errorf("failed to create msg: %s", err)
 						}
-						accSeq = accSeq + 1
-						if resp.TxResponse.Code != 0 {
-							if resp.TxResponse.Code == 0x14 {
-								log.Warn().Msg("mempool is full, stopping")
-								accSeq = accSeq - 1
-								break loop
+						for sent < txNum {
+							txByte, err := tx.IbcSign(ctx, accSeq, accNum, privKey, msgs...)
+							if err != nil {
+								return fmt.Errorf("failed to sign and broadcast: %s", err)
+							}
+							resp, err := client.GRPC.BroadcastTx(ctx, txByte)
+							//log.Info().Msgf("took %s broadcasting txs", resp)
+							if err != nil {
+								return fmt.Errorf("broadcast tx: %w", err)
+							}
+							accSeq = accSeq + 1
+							if resp.TxResponse.Code != 0 {
+								if resp.TxResponse.Code == 0x14 {
+									log.Warn().Msg("mempool is full, stopping")
+									metrics.MempoolFullTotal.WithLabelValues(chainID).Inc()
+									metrics.TxBroadcastTotal.WithLabelValues(chainID, "mempool_full").Inc()
+									accSeq = accSeq - 1
+									break loop
+								}
+								metrics.TxBroadcastTotal.WithLabelValues(chainID, "failed").Inc()
+							} else {
+								metrics.TxBroadcastTotal.WithLabelValues(chainID, "success").Inc()
 							}
+							sent++
 						}
-						sent++
 					}
 				}
 				log.Debug().Msgf("took %s broadcasting txs", time.Since(started))
@@ -179,6 +255,15 @@ msg-num: how many transaction messages to be included in a transaction
 				if err := rpcclient.WaitForHeight(client.RPC, targetHeight, nil); err != nil {
 					return fmt.Errorf("wait for height: %w", err)
 				}
+				if broadcaster != nil {
+					broadcaster.NextBlock()
+					if err := broadcaster.Flush(ctx); err != nil {
+						return fmt.Errorf("flush broadcaster: %w", err)
+					}
+					totalSent, _ := broadcaster.Counts()
+					sent = totalSent - prevBroadcasterSent
+					prevBroadcasterSent = totalSent
+				}
 				r, err := client.RPC.Block(ctx, &targetHeight)
 				if err != nil {
 					return err
@@ -190,8 +275,10 @@ msg-num: how many transaction messages to be included in a transaction
 				} else {
 					blockDuration = r.Block.Time.Sub(bt)
 					delete(blockTimes, targetHeight-1)
+					metrics.BlockDurationSeconds.WithLabelValues(chainID).Observe(blockDuration.Seconds())
 				}
 				blockTimes[targetHeight] = r.Block.Time
+				metrics.TxCommittedTotal.WithLabelValues(chainID, chainID).Add(float64(len(r.Block.Txs)))
 				log.Info().
 					Int64("height", targetHeight).
 					Str("block-time", r.Block.Time.Format(time.RFC3339Nano)).
@@ -202,12 +289,20 @@ msg-num: how many transaction messages to be included in a transaction
 				targetHeight++
 			}
 
+			if broadcaster != nil {
+				sent, failed := broadcaster.Close()
+				log.Info().Int("sent", sent).Int("failed", failed).Msg("broadcaster drained")
+			}
+
 			return nil
 		},
 	}
 	cmd.Flags().String(flagPacketTimeoutHeight, ibctypes.DefaultRelativePacketTimeoutHeight, "Packet timeout block height. The timeout is disabled when set to 0-0.")
 	cmd.Flags().Uint64(flagPacketTimeoutTimestamp, ibctypes.DefaultRelativePacketTimeoutTimestamp, "Packet timeout timestamp in nanoseconds. Default is 10 minutes. The timeout is disabled when set to 0.")
 	cmd.Flags().Bool(flagAbsoluteTimeouts, false, "Timeout flags are used as absolute timeouts.")
+	cmd.Flags().Int(flagWorkers, 1, "Number of concurrent signer/broadcaster workers. >1 enables the parallel broadcast pipeline.")
+	cmd.Flags().Int(flagInflight, 0, "Depth of the in-flight job queue when --workers > 1, defaults to --workers.")
+	cmd.Flags().String(flagMetricsAddr, "", "Address to expose Prometheus metrics on, e.g. :9090. Disabled when empty.")
 	flags.AddTxFlagsToCmd(cmd)
 	return cmd
 }
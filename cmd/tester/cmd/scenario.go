@@ -0,0 +1,443 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b-harvest/modules-test-tool/client"
+	"github.com/b-harvest/modules-test-tool/config"
+	"github.com/b-harvest/modules-test-tool/tx"
+	"github.com/b-harvest/modules-test-tool/wallet"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	ibctypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	flagScenarioOut = "out"
+
+	scenarioModeSequential = "sequential"
+	scenarioModeParallel   = "parallel"
+)
+
+// ScenarioFile is the on-disk, versioned description of one or more IBC
+// load-test runs. It is the input to `tester scenario run`.
+type ScenarioFile struct {
+	Version           int             `yaml:"version"`
+	Mode              string          `yaml:"mode"`
+	Concurrency       int             `yaml:"concurrency"`
+	StopOnMempoolFull bool            `yaml:"stopOnMempoolFull"`
+	Stages            []ScenarioStage `yaml:"stages"`
+}
+
+// ScenarioStage describes a single transfer run between two chains already
+// registered under config.IBCconfig.Chains.
+type ScenarioStage struct {
+	Name                string            `yaml:"name"`
+	Src                 string            `yaml:"src"`
+	Dst                 string            `yaml:"dst"`
+	Coin                string            `yaml:"coin"`
+	MsgsPerTx           int               `yaml:"msgsPerTx"`
+	TxsPerBlock         int               `yaml:"txsPerBlock"`
+	Blocks              int               `yaml:"blocks"`
+	RatePerSecond       int               `yaml:"ratePerSecond"`
+	MnemonicIndex       int               `yaml:"mnemonicIndex"`
+	PacketTimeoutHeight string            `yaml:"packetTimeoutHeight"`
+	Expected            *ScenarioExpected `yaml:"expected"`
+}
+
+// ScenarioExpected holds the pass/fail assertions checked against a stage's
+// report once it has finished running.
+type ScenarioExpected struct {
+	MinCommittedTxsPerBlock int    `yaml:"minCommittedTxsPerBlock"`
+	MaxBlockDuration        string `yaml:"maxBlockDuration"`
+}
+
+// StageReport is the machine-readable result of running a single stage,
+// written as one JSON object per stage so results can be diffed across runs.
+type StageReport struct {
+	Stage       string        `json:"stage"`
+	Src         string        `json:"src"`
+	Dst         string        `json:"dst"`
+	Blocks      []BlockReport `json:"blocks"`
+	Passed      bool          `json:"passed"`
+	FailureText string        `json:"failureText,omitempty"`
+	// MempoolFull records whether the stage stopped broadcasting early
+	// because it hit a mempool-full response, so callers can honor
+	// ScenarioFile.StopOnMempoolFull.
+	MempoolFull bool `json:"mempoolFull,omitempty"`
+}
+
+// BlockReport captures broadcast-vs-committed counts and timing for one
+// block of a stage.
+type BlockReport struct {
+	Height        int64         `json:"height"`
+	BroadcastTxs  int           `json:"broadcastTxs"`
+	CommittedTxs  int           `json:"committedTxs"`
+	BlockDuration time.Duration `json:"blockDuration"`
+}
+
+func ScenarioCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Run declarative IBC load-test scenarios",
+	}
+	cmd.AddCommand(scenarioRunCmd())
+	return cmd
+}
+
+// loadScenarioFile reads and validates the scenario file at path.
+func loadScenarioFile(path string) (ScenarioFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ScenarioFile{}, fmt.Errorf("failed to read scenario file: %s", err)
+	}
+	var scenario ScenarioFile
+	if err := yaml.Unmarshal(raw, &scenario); err != nil {
+		return ScenarioFile{}, fmt.Errorf("failed to parse scenario file: %s", err)
+	}
+	if scenario.Version != 1 {
+		return ScenarioFile{}, fmt.Errorf("unsupported scenario version: %d", scenario.Version)
+	}
+	if len(scenario.Stages) == 0 {
+		return ScenarioFile{}, fmt.Errorf("scenario has no stages")
+	}
+	return scenario, nil
+}
+
+// runScenarioFile runs every stage of scenario against cfg, sequentially or
+// in parallel depending on scenario.Mode, writing a JSON report per stage
+// to outPath (or stdout when outPath is empty).
+func runScenarioFile(cmd *cobra.Command, cfg *config.Config, scenario ScenarioFile, outPath string) error {
+	switch scenario.Mode {
+	case "", scenarioModeSequential:
+		for _, stage := range scenario.Stages {
+			report := runScenarioStage(cmd, cfg, stage)
+			if err := writeStageReport(outPath, report); err != nil {
+				return err
+			}
+			if !report.Passed {
+				return fmt.Errorf("stage %q failed: %s", report.Stage, report.FailureText)
+			}
+			if scenario.StopOnMempoolFull && report.MempoolFull {
+				return fmt.Errorf("stopping scenario: stage %q hit a full mempool and stopOnMempoolFull is set", report.Stage)
+			}
+		}
+	case scenarioModeParallel:
+		concurrency := scenario.Concurrency
+		if concurrency <= 0 {
+			concurrency = len(scenario.Stages)
+		}
+		sem := make(chan struct{}, concurrency)
+		var wait sync.WaitGroup
+		var stopMu sync.Mutex
+		var stopped bool
+		reports := make([]*StageReport, len(scenario.Stages))
+		for i, stage := range scenario.Stages {
+			stopMu.Lock()
+			halt := stopped
+			stopMu.Unlock()
+			if halt {
+				break
+			}
+			sem <- struct{}{}
+			wait.Add(1)
+			go func(i int, stage ScenarioStage) {
+				defer wait.Done()
+				defer func() { <-sem }()
+				report := runScenarioStage(cmd, cfg, stage)
+				reports[i] = report
+				if scenario.StopOnMempoolFull && report.MempoolFull {
+					stopMu.Lock()
+					stopped = true
+					stopMu.Unlock()
+				}
+			}(i, stage)
+		}
+		wait.Wait()
+		for _, report := range reports {
+			if report == nil {
+				continue
+			}
+			if err := writeStageReport(outPath, report); err != nil {
+				return err
+			}
+			if !report.Passed {
+				return fmt.Errorf("stage %q failed: %s", report.Stage, report.FailureText)
+			}
+		}
+		if stopped {
+			return fmt.Errorf("stopping scenario: a stage hit a full mempool and stopOnMempoolFull is set")
+		}
+	default:
+		return fmt.Errorf("unknown scenario mode: %s", scenario.Mode)
+	}
+	return nil
+}
+
+func scenarioRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "run [scenario-file]",
+		Short:   "Run the stages described in a scenario file",
+		Aliases: []string{"r"},
+		Args:    cobra.ExactArgs(1),
+		Long: `Run a versioned scenario file describing one or more IBC load-test runs.
+
+Example: $tester scenario run ./scenarios/basic-transfer.yaml
+
+A scenario declares a list of transfer stages resolved against the chains in
+config.IBCconfig, and is executed sequentially or in parallel depending on
+the top-level "mode" field. A JSON report is printed per stage.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := SetLogger(logLevel)
+			if err != nil {
+				return err
+			}
+
+			scenario, err := loadScenarioFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Read(config.DefaultConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to read config file: %s", err)
+			}
+
+			outPath, err := cmd.Flags().GetString(flagScenarioOut)
+			if err != nil {
+				return err
+			}
+
+			return runScenarioFile(cmd, cfg, scenario, outPath)
+		},
+	}
+	cmd.Flags().String(flagScenarioOut, "", "file to append JSON stage reports to, defaults to stdout")
+	return cmd
+}
+
+func runScenarioStage(cmd *cobra.Command, cfg *config.Config, stage ScenarioStage) *StageReport {
+	report := &StageReport{Stage: stage.Name, Src: stage.Src, Dst: stage.Dst}
+
+	var srcChain, dstChain config.IBCchain
+	var srcFound, dstFound bool
+	for _, c := range cfg.IBCconfig.Chains {
+		if c.ChainId == stage.Src {
+			srcChain = c
+			srcFound = true
+		}
+		if c.ChainId == stage.Dst {
+			dstChain = c
+			dstFound = true
+		}
+	}
+	if !srcFound || !dstFound {
+		report.FailureText = fmt.Sprintf("src %q or dst %q not found in config.IBCconfig.Chains", stage.Src, stage.Dst)
+		return report
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cl, err := client.NewClient(srcChain.Rpc, srcChain.Grpc)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("failed to connect clients: %s", err)
+		return report
+	}
+	defer cl.Stop() // nolint: errcheck
+
+	ibcclientCtx := cl.GetCLIContext()
+	chainID, err := cl.RPC.GetNetworkChainID(ctx)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("failed to get chain id: %s", err)
+		return report
+	}
+
+	coin, err := sdktypes.ParseCoinNormalized(stage.Coin)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("failed to parse coin: %s", err)
+		return report
+	}
+	if !strings.HasPrefix(coin.Denom, "ibc/") {
+		denomTrace := ibctypes.ParseDenomTrace(coin.Denom)
+		coin.Denom = denomTrace.IBCDenom()
+	}
+
+	if stage.MnemonicIndex < 0 || stage.MnemonicIndex >= len(cfg.Custom.Mnemonics) {
+		report.FailureText = fmt.Sprintf("mnemonicIndex %d out of range, config has %d mnemonics", stage.MnemonicIndex, len(cfg.Custom.Mnemonics))
+		return report
+	}
+	mnemonic := cfg.Custom.Mnemonics[stage.MnemonicIndex]
+	accAddr, privKey, err := wallet.IBCRecoverAccountFromMnemonic(mnemonic, "", srcChain.AccountHD, srcChain.AccountaddrPrefix)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("failed to retrieve account from mnemonic: %s", err)
+		return report
+	}
+
+	grpcclient := cl.GRPC
+	chainTrace, err := grpcclient.AllChainsTrace(ctx)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("failed to fetch channel trace: %s", err)
+		return report
+	}
+	var srcPort, srcChannel string
+	for _, t := range chainTrace {
+		if t.ClientChainId == dstChain.ChainId {
+			srcPort = "transfer"
+			srcChannel = t.ChannelId
+			break
+		}
+	}
+	if srcChannel == "" {
+		report.FailureText = fmt.Sprintf("no open channel found from %q to %q", stage.Src, stage.Dst)
+		return report
+	}
+
+	gasLimit := uint64(cfg.Custom.GasLimit)
+	fees := sdktypes.NewCoins(sdktypes.NewCoin(srcChain.TokenDenom, sdktypes.NewInt(cfg.Custom.FeeAmount)))
+	memo := cfg.Custom.Memo
+	txClient := tx.IbcNewtransaction(cl, chainID, gasLimit, fees, memo)
+
+	account, err := cl.GRPC.GetBaseAccountInfo(ctx, accAddr)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("failed to get account information: %s", err)
+		return report
+	}
+	accSeq := account.GetSequence()
+	accNum := account.GetAccountNumber()
+
+	st, err := cl.RPC.Status(ctx)
+	if err != nil {
+		report.FailureText = fmt.Sprintf("get status: %s", err)
+		return report
+	}
+	startingHeight := st.SyncInfo.LatestBlockHeight + 2
+	if err := rpcclient.WaitForHeight(cl.RPC, startingHeight-1, nil); err != nil {
+		report.FailureText = fmt.Sprintf("wait for height: %s", err)
+		return report
+	}
+	targetHeight := startingHeight
+
+	var ratePause time.Duration
+	if stage.RatePerSecond > 0 {
+		ratePause = time.Second / time.Duration(stage.RatePerSecond)
+	}
+
+	var prevBlockTime time.Time
+	for i := 0; i < stage.Blocks; i++ {
+		started := time.Now()
+		sent := 0
+	loop:
+		for sent < stage.TxsPerBlock {
+			msgs, err := txClient.CreateTransferBot(cmd, ibcclientCtx, srcPort, srcChannel, coin, accAddr, dstChain.DstAddress, stage.MsgsPerTx)
+			if err != nil {
+				report.FailureText = fmt.Sprintf("failed to create msg: %s", err)
+				return report
+			}
+			for sent < stage.TxsPerBlock {
+				txByte, err := txClient.IbcSign(ctx, accSeq, accNum, privKey, msgs...)
+				if err != nil {
+					report.FailureText = fmt.Sprintf("failed to sign and broadcast: %s", err)
+					return report
+				}
+				resp, err := cl.GRPC.BroadcastTx(ctx, txByte)
+				if err != nil {
+					report.FailureText = fmt.Sprintf("broadcast tx: %s", err)
+					return report
+				}
+				accSeq++
+				if resp.TxResponse.Code == 0x14 {
+					log.Warn().Str("stage", stage.Name).Msg("mempool is full, stopping stage")
+					accSeq--
+					report.MempoolFull = true
+					break loop
+				}
+				sent++
+				if ratePause > 0 {
+					time.Sleep(ratePause)
+				}
+			}
+		}
+		log.Debug().Str("stage", stage.Name).Dur("took", time.Since(started)).Msg("broadcast txs")
+
+		if err := rpcclient.WaitForHeight(cl.RPC, targetHeight, nil); err != nil {
+			report.FailureText = fmt.Sprintf("wait for height: %s", err)
+			return report
+		}
+		r, err := cl.RPC.Block(ctx, &targetHeight)
+		if err != nil {
+			report.FailureText = err.Error()
+			return report
+		}
+		var blockDuration time.Duration
+		if !prevBlockTime.IsZero() {
+			blockDuration = r.Block.Time.Sub(prevBlockTime)
+		}
+		prevBlockTime = r.Block.Time
+		report.Blocks = append(report.Blocks, BlockReport{
+			Height:        targetHeight,
+			BroadcastTxs:  sent,
+			CommittedTxs:  len(r.Block.Txs),
+			BlockDuration: blockDuration,
+		})
+		targetHeight++
+	}
+
+	report.Passed = evaluateExpected(stage.Expected, report)
+	return report
+}
+
+func evaluateExpected(expected *ScenarioExpected, report *StageReport) bool {
+	if expected == nil {
+		return true
+	}
+	var maxDuration time.Duration
+	if expected.MaxBlockDuration != "" {
+		d, err := time.ParseDuration(expected.MaxBlockDuration)
+		if err == nil {
+			maxDuration = d
+		}
+	}
+	for _, b := range report.Blocks {
+		if b.CommittedTxs < expected.MinCommittedTxsPerBlock {
+			report.FailureText = fmt.Sprintf("block %d committed %d txs, want at least %d", b.Height, b.CommittedTxs, expected.MinCommittedTxsPerBlock)
+			return false
+		}
+		if maxDuration > 0 && b.BlockDuration > maxDuration {
+			report.FailureText = fmt.Sprintf("block %d took %s, want at most %s", b.Height, b.BlockDuration, maxDuration)
+			return false
+		}
+	}
+	return true
+}
+
+func writeStageReport(outPath string, report *StageReport) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stage report: %s", err)
+	}
+	if outPath == "" {
+		fmt.Println(string(b))
+		return nil
+	}
+	f, err := os.OpenFile(outPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open report file: %s", err)
+	}
+	defer f.Close() // nolint: errcheck
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write report file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEvaluateExpectedNil(t *testing.T) {
+	report := &StageReport{}
+	if !evaluateExpected(nil, report) {
+		t.Fatal("expected nil expectations to pass")
+	}
+}
+
+func TestEvaluateExpectedMinCommittedTxsPerBlock(t *testing.T) {
+	expected := &ScenarioExpected{MinCommittedTxsPerBlock: 5}
+	report := &StageReport{Blocks: []BlockReport{{Height: 10, CommittedTxs: 3}}}
+	if evaluateExpected(expected, report) {
+		t.Fatal("expected a block under MinCommittedTxsPerBlock to fail")
+	}
+	if report.FailureText == "" {
+		t.Fatal("expected a failure reason to be recorded")
+	}
+}
+
+func TestEvaluateExpectedMaxBlockDuration(t *testing.T) {
+	expected := &ScenarioExpected{MaxBlockDuration: "1s"}
+	report := &StageReport{Blocks: []BlockReport{{Height: 1, BlockDuration: 2e9}}}
+	if evaluateExpected(expected, report) {
+		t.Fatal("expected a block over MaxBlockDuration to fail")
+	}
+}
+
+func TestEvaluateExpectedInvalidMaxBlockDurationIsIgnored(t *testing.T) {
+	expected := &ScenarioExpected{MaxBlockDuration: "not-a-duration"}
+	report := &StageReport{Blocks: []BlockReport{{Height: 1, BlockDuration: 1000}}}
+	if !evaluateExpected(expected, report) {
+		t.Fatal("expected an unparseable MaxBlockDuration to be ignored rather than fail the stage")
+	}
+}
+
+func TestLoadScenarioFileRejectsUnknownVersion(t *testing.T) {
+	path := writeTempScenario(t, "version: 2\nstages:\n- name: s\n")
+	if _, err := loadScenarioFile(path); err == nil {
+		t.Fatal("expected an unsupported version to be rejected")
+	}
+}
+
+func TestLoadScenarioFileRejectsNoStages(t *testing.T) {
+	path := writeTempScenario(t, "version: 1\nstages: []\n")
+	if _, err := loadScenarioFile(path); err == nil {
+		t.Fatal("expected a scenario with no stages to be rejected")
+	}
+}
+
+func writeTempScenario(t *testing.T, contents string) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "scenario-*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp scenario file: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("failed to write temp scenario file: %s", err)
+	}
+	return f.Name()
+}
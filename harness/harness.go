@@ -0,0 +1,262 @@
+// Package harness spins up a local, ephemeral multi-chain IBC setup
+// (simd/gaiad/osmosisd containers plus a relayer) so load tests described
+// by a scenario file can be reproduced on a laptop without a preexisting
+// testnet.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/b-harvest/modules-test-tool/config"
+	"github.com/b-harvest/modules-test-tool/relayer"
+	"github.com/b-harvest/modules-test-tool/wallet"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ChainConfig describes one local chain the harness should bring up.
+type ChainConfig struct {
+	Name          string
+	Image         string
+	Tag           string
+	Binary        string
+	Denom         string
+	AccountPrefix string
+	// CoinType is the HD path coin type, matching config.IBCchain's AccountHD.
+	CoinType uint32
+	RpcPort  int
+	GrpcPort int
+}
+
+// Config is the top-level harness description: the chains to run, the
+// relayer image used to link them, and the mnemonics to fund at genesis.
+type Config struct {
+	Chains       []ChainConfig
+	RelayerImage string
+	RelayerHome  string
+	Mnemonics    []string
+}
+
+// chainHandle tracks the running container backing one ChainConfig.
+type chainHandle struct {
+	cfg         ChainConfig
+	containerID string
+}
+
+// Harness owns the lifecycle of every container it starts.
+type Harness struct {
+	cfg       Config
+	chains    []*chainHandle
+	relayer   *relayer.Relayer
+	relayerID string
+}
+
+// New returns a Harness that has not yet started any containers.
+func New(cfg Config) *Harness {
+	return &Harness{cfg: cfg}
+}
+
+// Up starts every configured chain container idling ahead of genesis, funds
+// the genesis accounts from cfg.Mnemonics, starts each chain's node process,
+// waits for its RPC endpoint to come up, starts the relayer container, and
+// links every chain pair. It returns the resulting config.IBCchain entries.
+func (h *Harness) Up(ctx context.Context) ([]config.IBCchain, error) {
+	for _, chainCfg := range h.cfg.Chains {
+		containerID, err := startChainContainer(ctx, chainCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start chain %q: %s", chainCfg.Name, err)
+		}
+		h.chains = append(h.chains, &chainHandle{cfg: chainCfg, containerID: containerID})
+	}
+
+	for _, handle := range h.chains {
+		if err := fundGenesisAccounts(ctx, handle, h.cfg.Mnemonics); err != nil {
+			return nil, fmt.Errorf("failed to fund genesis accounts on %q: %s", handle.cfg.Name, err)
+		}
+		if err := startChainNode(ctx, handle); err != nil {
+			return nil, fmt.Errorf("failed to start node on %q: %s", handle.cfg.Name, err)
+		}
+		if err := waitForRPC(ctx, handle); err != nil {
+			return nil, fmt.Errorf("chain %q never became ready: %s", handle.cfg.Name, err)
+		}
+	}
+
+	relayerID, err := startRelayerContainer(ctx, h.cfg.RelayerImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start relayer: %s", err)
+	}
+	h.relayerID = relayerID
+	h.relayer = relayer.NewContainerRelayer("rly", h.cfg.RelayerHome, relayerID)
+
+	for _, path := range chainPathPairs(h.chains) {
+		if _, _, err := h.relayer.EnsureChannel(ctx, path, relayer.DefaultCreateChannelOptions()); err != nil {
+			return nil, fmt.Errorf("failed to link %q: %s", path, err)
+		}
+	}
+
+	return chainIBCEntries(h.chains), nil
+}
+
+// chainPathPairs returns the relayer path name for every unique pair of
+// configured chains (not every ordered combination).
+func chainPathPairs(chains []*chainHandle) []string {
+	var paths []string
+	for i, a := range chains {
+		for _, b := range chains[i+1:] {
+			paths = append(paths, fmt.Sprintf("%s-%s", a.cfg.Name, b.cfg.Name))
+		}
+	}
+	return paths
+}
+
+func chainIBCEntries(handles []*chainHandle) []config.IBCchain {
+	var chains []config.IBCchain
+	for _, handle := range handles {
+		chains = append(chains, config.IBCchain{
+			ChainId:           handle.cfg.Name,
+			Rpc:               fmt.Sprintf("tcp://localhost:%d", handle.cfg.RpcPort),
+			Grpc:              fmt.Sprintf("localhost:%d", handle.cfg.GrpcPort),
+			TokenDenom:        handle.cfg.Denom,
+			AccountHD:         handle.cfg.CoinType,
+			AccountaddrPrefix: handle.cfg.AccountPrefix,
+		})
+	}
+	return chains
+}
+
+// Down stops and removes every container the harness started, best-effort.
+func (h *Harness) Down(ctx context.Context) error {
+	var errs []string
+	if h.relayerID != "" {
+		if err := removeContainer(ctx, h.relayerID); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, handle := range h.chains {
+		if err := removeContainer(ctx, handle.containerID); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to tear down harness: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Wipe removes the harness's containers along with their volumes.
+func (h *Harness) Wipe(ctx context.Context) error {
+	ids := make([]string, 0, len(h.chains)+1)
+	if h.relayerID != "" {
+		ids = append(ids, h.relayerID)
+	}
+	for _, handle := range h.chains {
+		ids = append(ids, handle.containerID)
+	}
+	var errs []string
+	for _, id := range ids {
+		if err := docker(ctx, "rm", "-f", "-v", id); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to remove container %q: %s", id, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to wipe harness: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// startChainContainer brings the container up idling on its entrypoint
+// rather than the node binary itself, so fundGenesisAccounts gets a chance
+// to seed the genesis file before anything reads it. startChainNode is what
+// actually launches the node, once genesis accounts are in place.
+func startChainContainer(ctx context.Context, chainCfg ChainConfig) (string, error) {
+	image := fmt.Sprintf("%s:%s", chainCfg.Image, chainCfg.Tag)
+	args := []string{"run", "-d",
+		"--name", fmt.Sprintf("modules-test-tool-%s", chainCfg.Name),
+		"-p", fmt.Sprintf("%d:26657", chainCfg.RpcPort),
+		"-p", fmt.Sprintf("%d:9090", chainCfg.GrpcPort),
+		"--entrypoint", "sh",
+		image, "-c", "sleep infinity",
+	}
+	log.Info().Str("chain", chainCfg.Name).Str("image", image).Msg("starting chain container")
+	out, err := dockerOutput(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// startChainNode launches the node binary inside an already-running,
+// already-funded container, detached so Up can move on to waitForRPC
+// instead of blocking on the node's lifetime.
+func startChainNode(ctx context.Context, handle *chainHandle) error {
+	return docker(ctx, "exec", "-d", handle.containerID, handle.cfg.Binary, "start")
+}
+
+func startRelayerContainer(ctx context.Context, image string) (string, error) {
+	args := []string{"run", "-d", "--name", "modules-test-tool-relayer", "--network", "host", image}
+	log.Info().Str("image", image).Msg("starting relayer container")
+	out, err := dockerOutput(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func removeContainer(ctx context.Context, containerID string) error {
+	if err := docker(ctx, "rm", "-f", containerID); err != nil {
+		return fmt.Errorf("failed to remove container %q: %s", containerID, err)
+	}
+	return nil
+}
+
+func waitForRPC(ctx context.Context, handle *chainHandle) error {
+	deadline := time.Now().Add(2 * time.Minute)
+	for time.Now().Before(deadline) {
+		if err := docker(ctx, "exec", handle.containerID, handle.cfg.Binary, "status"); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+	return fmt.Errorf("timed out waiting for RPC on container %q", handle.containerID)
+}
+
+// fundGenesisAccounts runs add-genesis-account for every mnemonic against
+// the container's genesis file. It must run before startChainNode: once the
+// node process is up the genesis file has already been consumed, and
+// add-genesis-account against a live chain funds nobody.
+func fundGenesisAccounts(ctx context.Context, handle *chainHandle, mnemonics []string) error {
+	for i, mnemonic := range mnemonics {
+		accAddr, _, err := wallet.IBCRecoverAccountFromMnemonic(mnemonic, "", handle.cfg.CoinType, handle.cfg.AccountPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to recover account from mnemonic %d: %s", i, err)
+		}
+		log.Debug().Str("chain", handle.cfg.Name).Int("index", i).Str("address", accAddr.String()).Msg("funding genesis account")
+		if err := docker(ctx, "exec", handle.containerID, handle.cfg.Binary,
+			"add-genesis-account", accAddr.String(), fmt.Sprintf("100000000%s", handle.cfg.Denom)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func docker(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, string(out))
+	}
+	return nil
+}
+
+func dockerOutput(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	return cmd.Output()
+}
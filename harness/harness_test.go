@@ -0,0 +1,43 @@
+package harness
+
+import "testing"
+
+func TestChainPathPairs(t *testing.T) {
+	chains := []*chainHandle{
+		{cfg: ChainConfig{Name: "a"}},
+		{cfg: ChainConfig{Name: "b"}},
+		{cfg: ChainConfig{Name: "c"}},
+	}
+	got := chainPathPairs(chains)
+	want := []string{"a-b", "a-c", "b-c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestChainIBCEntries(t *testing.T) {
+	handles := []*chainHandle{
+		{cfg: ChainConfig{
+			Name:          "chain-a",
+			Denom:         "uatom",
+			AccountPrefix: "cosmos",
+			CoinType:      118,
+			RpcPort:       26657,
+			GrpcPort:      9090,
+		}},
+	}
+	entries := chainIBCEntries(handles)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.ChainId != "chain-a" || e.Rpc != "tcp://localhost:26657" || e.Grpc != "localhost:9090" ||
+		e.TokenDenom != "uatom" || e.AccountHD != 118 || e.AccountaddrPrefix != "cosmos" {
+		t.Fatalf("unexpected config.IBCchain: %+v", e)
+	}
+}
@@ -0,0 +1,84 @@
+// Package metrics exposes Prometheus counters/histograms for live
+// observability of IBC load-test runs.
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// TxBroadcastTotal counts every broadcast attempt, labeled by chain and
+	// result ("success", "failed", "mempool_full").
+	TxBroadcastTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ibc_tx_broadcast_total",
+		Help: "Total number of IBC transfer transactions broadcast.",
+	}, []string{"chain", "result"})
+
+	// TxCommittedTotal adds the number of txs in each block a transfer run
+	// waits on, labeled by the src/dst chain pair of that run. Every tx in
+	// the block is counted, so runs that share a src chain each count the
+	// same block. The single-pair transfer command does not resolve its dst
+	// chain and sets both labels to the src chain id.
+	TxCommittedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ibc_tx_committed_total",
+		Help: "Total number of IBC transfer transactions committed in a block.",
+	}, []string{"srcchain", "dstchain"})
+
+	// BlockDurationSeconds observes the wall-clock time between consecutive
+	// committed blocks, labeled by chain.
+	BlockDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ibc_block_duration_seconds",
+		Help:    "Observed duration between consecutive committed blocks.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"chain"})
+
+	// MempoolFullTotal counts how often a broadcast was rejected with the
+	// mempool-full ABCI code, labeled by chain.
+	MempoolFullTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ibc_mempool_full_total",
+		Help: "Total number of broadcasts rejected because the mempool was full.",
+	}, []string{"chain"})
+
+	// PacketAckLatencySeconds observes the time between send_packet on the
+	// src chain and acknowledge_packet on the src chain (relayed through the
+	// dst chain), labeled by the src/dst chain pair.
+	PacketAckLatencySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ibc_packet_ack_latency_seconds",
+		Help:    "Observed end-to-end latency from send_packet to acknowledge_packet.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"srcchain", "dstchain"})
+)
+
+// StartServer exposes the registered metrics on addr at /metrics and
+// returns the *http.Server so callers can shut it down on exit. A failure
+// to bind is logged but does not abort the calling command.
+func StartServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		log.Info().Str("addr", addr).Msg("starting metrics server")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("metrics server stopped")
+		}
+	}()
+
+	return server
+}
+
+// Shutdown gracefully stops a server returned by StartServer.
+func Shutdown(ctx context.Context, server *http.Server) {
+	if server == nil {
+		return
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to shut down metrics server")
+	}
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tmhttp "github.com/tendermint/tendermint/rpc/client/http"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	querySendPacket         = "tm.event='Tx' AND send_packet.packet_sequence EXISTS"
+	queryRecvPacket         = "tm.event='Tx' AND recv_packet.packet_sequence EXISTS"
+	queryAcknowledgePacket  = "tm.event='Tx' AND acknowledge_packet.packet_sequence EXISTS"
+	packetTrackerSubscriber = "modules-test-tool-packet-tracker"
+
+	// packetSentTTL bounds how long a send_packet observation waits for its
+	// ack before evictStale drops it, so a packet that times out or is never
+	// relayed doesn't leak its sentAt entry for the rest of a soak test.
+	packetSentTTL     = 10 * time.Minute
+	packetSweepPeriod = time.Minute
+)
+
+// packetKey identifies one packet by its source port/channel and sequence
+// number, which is unique for the lifetime of a channel.
+type packetKey struct {
+	srcPort    string
+	srcChannel string
+	sequence   string
+}
+
+// PacketTracker subscribes to send_packet/recv_packet/acknowledge_packet
+// events on a src and dst RPC endpoint and turns them into
+// ibc_packet_ack_latency_seconds observations.
+type PacketTracker struct {
+	srcChain string
+	dstChain string
+
+	srcClient *tmhttp.HTTP
+	dstClient *tmhttp.HTTP
+
+	sentAt map[packetKey]time.Time
+}
+
+// NewPacketTracker dials websocket RPC clients against srcRPC and dstRPC.
+func NewPacketTracker(srcChain, dstChain, srcRPC, dstRPC string) (*PacketTracker, error) {
+	srcClient, err := tmhttp.New(srcRPC, "/websocket")
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial src rpc: %s", err)
+	}
+	dstClient, err := tmhttp.New(dstRPC, "/websocket")
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial dst rpc: %s", err)
+	}
+	return &PacketTracker{
+		srcChain:  srcChain,
+		dstChain:  dstChain,
+		srcClient: srcClient,
+		dstClient: dstClient,
+		sentAt:    make(map[packetKey]time.Time),
+	}, nil
+}
+
+// Run starts both clients and blocks, forwarding events into the
+// ibc_packet_ack_latency_seconds histogram until ctx is cancelled.
+// evictStale runs on packetSweepPeriod in the same loop.
+func (p *PacketTracker) Run(ctx context.Context) error {
+	if err := p.srcClient.Start(); err != nil {
+		return fmt.Errorf("failed to start src rpc client: %s", err)
+	}
+	defer p.srcClient.Stop() // nolint: errcheck
+
+	if err := p.dstClient.Start(); err != nil {
+		return fmt.Errorf("failed to start dst rpc client: %s", err)
+	}
+	defer p.dstClient.Stop() // nolint: errcheck
+
+	sendCh, err := p.srcClient.Subscribe(ctx, packetTrackerSubscriber, querySendPacket)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to send_packet: %s", err)
+	}
+	recvCh, err := p.dstClient.Subscribe(ctx, packetTrackerSubscriber, queryRecvPacket)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to recv_packet: %s", err)
+	}
+	ackCh, err := p.srcClient.Subscribe(ctx, packetTrackerSubscriber, queryAcknowledgePacket)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to acknowledge_packet: %s", err)
+	}
+
+	sweep := time.NewTicker(packetSweepPeriod)
+	defer sweep.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt := <-sendCh:
+			p.onSendPacket(evt)
+		case evt := <-recvCh:
+			p.onRecvPacket(evt)
+		case evt := <-ackCh:
+			p.onAcknowledgePacket(evt)
+		case now := <-sweep.C:
+			p.evictStale(now)
+		}
+	}
+}
+
+func (p *PacketTracker) onSendPacket(evt ctypes.ResultEvent) {
+	key, ok := packetKeyFromEvent(evt, "send_packet")
+	if !ok {
+		return
+	}
+	p.sentAt[key] = time.Now()
+}
+
+func (p *PacketTracker) onRecvPacket(evt ctypes.ResultEvent) {
+	log.Debug().Str("srcchain", p.srcChain).Str("dstchain", p.dstChain).Msg("observed recv_packet")
+}
+
+func (p *PacketTracker) onAcknowledgePacket(evt ctypes.ResultEvent) {
+	key, ok := packetKeyFromEvent(evt, "acknowledge_packet")
+	if !ok {
+		return
+	}
+	sentAt, ok := p.sentAt[key]
+	if !ok {
+		return
+	}
+	delete(p.sentAt, key)
+	PacketAckLatencySeconds.WithLabelValues(p.srcChain, p.dstChain).Observe(time.Since(sentAt).Seconds())
+}
+
+// evictStale drops sentAt entries older than packetSentTTL.
+func (p *PacketTracker) evictStale(now time.Time) {
+	for key, sentAt := range p.sentAt {
+		if now.Sub(sentAt) > packetSentTTL {
+			delete(p.sentAt, key)
+		}
+	}
+}
+
+func packetKeyFromEvent(evt ctypes.ResultEvent, eventType string) (packetKey, bool) {
+	ports, ok := evt.Events[eventType+".packet_src_port"]
+	if !ok || len(ports) == 0 {
+		return packetKey{}, false
+	}
+	channels, ok := evt.Events[eventType+".packet_src_channel"]
+	if !ok || len(channels) == 0 {
+		return packetKey{}, false
+	}
+	sequences, ok := evt.Events[eventType+".packet_sequence"]
+	if !ok || len(sequences) == 0 {
+		return packetKey{}, false
+	}
+	return packetKey{srcPort: ports[0], srcChannel: channels[0], sequence: sequences[0]}, true
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestPacketKeyFromEvent(t *testing.T) {
+	evt := ctypes.ResultEvent{
+		Events: map[string][]string{
+			"send_packet.packet_src_port":    {"transfer"},
+			"send_packet.packet_src_channel": {"channel-0"},
+			"send_packet.packet_sequence":    {"42"},
+		},
+	}
+	key, ok := packetKeyFromEvent(evt, "send_packet")
+	if !ok {
+		t.Fatal("expected packetKeyFromEvent to succeed")
+	}
+	want := packetKey{srcPort: "transfer", srcChannel: "channel-0", sequence: "42"}
+	if key != want {
+		t.Fatalf("got %+v, want %+v", key, want)
+	}
+}
+
+func TestPacketKeyFromEventMissingField(t *testing.T) {
+	evt := ctypes.ResultEvent{
+		Events: map[string][]string{
+			"send_packet.packet_src_port": {"transfer"},
+		},
+	}
+	if _, ok := packetKeyFromEvent(evt, "send_packet"); ok {
+		t.Fatal("expected packetKeyFromEvent to fail on a missing field")
+	}
+}
+
+func TestEvictStale(t *testing.T) {
+	now := time.Unix(1000, 0)
+	fresh := packetKey{srcPort: "transfer", srcChannel: "channel-0", sequence: "1"}
+	stale := packetKey{srcPort: "transfer", srcChannel: "channel-0", sequence: "2"}
+
+	p := &PacketTracker{
+		sentAt: map[packetKey]time.Time{
+			fresh: now.Add(-1 * time.Minute),
+			stale: now.Add(-packetSentTTL - time.Minute),
+		},
+	}
+
+	p.evictStale(now)
+
+	if _, ok := p.sentAt[stale]; ok {
+		t.Fatal("expected the stale entry to be evicted")
+	}
+	if _, ok := p.sentAt[fresh]; !ok {
+		t.Fatal("expected the fresh entry to survive")
+	}
+}
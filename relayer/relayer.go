@@ -0,0 +1,203 @@
+// Package relayer drives an external IBC relayer binary (the Go relayer,
+// `rly`, or `hermes`) to ensure a path/channel exists before a load test
+// starts.
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CreateChannelOptions mirrors the shape interchaintest uses for
+// ibc.CreateChannelOptions.
+type CreateChannelOptions struct {
+	SourcePortName string
+	DestPortName   string
+	Order          string
+	Version        string
+	// Override passes --override, forcing a fresh client/connection/channel
+	// instead of reusing a stale one.
+	Override bool
+}
+
+// DefaultCreateChannelOptions returns unordered transfer channels on ics20-1.
+func DefaultCreateChannelOptions() CreateChannelOptions {
+	return CreateChannelOptions{
+		SourcePortName: "transfer",
+		DestPortName:   "transfer",
+		Order:          "unordered",
+		Version:        "ics20-1",
+	}
+}
+
+// Relayer drives a relayer binary against an already-configured path name.
+type Relayer struct {
+	// Binary is the relayer executable to shell out to, e.g. "rly" or "hermes".
+	Binary string
+	// Home is passed as --home so multiple relayer instances don't collide
+	// on state.
+	Home string
+
+	// newCmd builds the *exec.Cmd for one invocation of Binary with args.
+	// It defaults to running Binary directly on the host.
+	newCmd func(ctx context.Context, binary string, args ...string) *exec.Cmd
+}
+
+// NewRelayer returns a Relayer driving the given binary directly on the host.
+func NewRelayer(binary, home string) *Relayer {
+	return &Relayer{Binary: binary, Home: home, newCmd: exec.CommandContext}
+}
+
+// NewContainerRelayer returns a Relayer that drives binary inside the
+// already-running Docker container containerID, via `docker exec`.
+func NewContainerRelayer(binary, home, containerID string) *Relayer {
+	return &Relayer{
+		Binary: binary,
+		Home:   home,
+		newCmd: func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "docker", append([]string{"exec", containerID, name}, args...)...)
+		},
+	}
+}
+
+// EnsureChannel creates the client, connection and channel for path if they
+// do not already exist, then blocks until the channel reaches
+// channel_open_confirm. It returns the resulting src port and channel.
+func (r *Relayer) EnsureChannel(ctx context.Context, path string, opts CreateChannelOptions) (srcPort, srcChannel string, err error) {
+	args := []string{"tx", "link", path,
+		"--src-port", opts.SourcePortName,
+		"--dst-port", opts.DestPortName,
+		"--order", opts.Order,
+		"--version", opts.Version,
+	}
+	if opts.Override {
+		args = append(args, "--override")
+	}
+
+	log.Info().Str("path", path).Bool("override", opts.Override).Msg("ensuring IBC channel via relayer")
+	if err := r.run(ctx, args...); err != nil {
+		return "", "", fmt.Errorf("failed to link path %q: %s", path, err)
+	}
+
+	if err := r.waitForChannelOpenConfirm(ctx, path); err != nil {
+		return "", "", err
+	}
+
+	srcPort, srcChannel, err = r.queryChannel(ctx, path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to query resulting channel: %s", err)
+	}
+	return srcPort, srcChannel, nil
+}
+
+// channelStateResult is the subset of `rly query channel --json` this tool
+// cares about.
+type channelStateResult struct {
+	State string `json:"state"`
+}
+
+// pathEnd is one side (src or dst) of `rly paths show <path> --json`.
+type pathEnd struct {
+	ChainID   string `json:"chain-id"`
+	PortID    string `json:"port-id"`
+	ChannelID string `json:"channel-id"`
+}
+
+// pathShowResult is the shape of `rly paths show <path> --json`.
+type pathShowResult struct {
+	Src pathEnd `json:"src"`
+	Dst pathEnd `json:"dst"`
+}
+
+func (r *Relayer) waitForChannelOpenConfirm(ctx context.Context, path string) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	timeout := time.After(2 * time.Minute)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for channel_open_confirm on path %q", path)
+		case <-ticker.C:
+			state, err := r.channelState(ctx, path)
+			if err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("failed to query channel state, retrying")
+				continue
+			}
+			if strings.ToUpper(state) == "STATE_OPEN" {
+				return nil
+			}
+		}
+	}
+}
+
+// channelState queries the channel's current state.
+func (r *Relayer) channelState(ctx context.Context, path string) (string, error) {
+	out, err := r.output(ctx, "query", "channel", path, "--json")
+	if err != nil {
+		return "", err
+	}
+	return parseChannelState(out)
+}
+
+func parseChannelState(out []byte) (string, error) {
+	var result channelStateResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		return "", fmt.Errorf("failed to parse channel state: %s", err)
+	}
+	return result.State, nil
+}
+
+// queryChannel resolves the src port/channel for path out of the relayer's
+// path JSON.
+func (r *Relayer) queryChannel(ctx context.Context, path string) (srcPort, srcChannel string, err error) {
+	out, err := r.output(ctx, "paths", "show", path, "--json")
+	if err != nil {
+		return "", "", err
+	}
+	return parsePathShow(path, out)
+}
+
+func parsePathShow(path string, out []byte) (srcPort, srcChannel string, err error) {
+	var result pathShowResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		return "", "", fmt.Errorf("failed to parse path %q: %s", path, err)
+	}
+	if result.Src.ChannelID == "" {
+		return "", "", fmt.Errorf("path %q has no src channel yet", path)
+	}
+	return result.Src.PortID, result.Src.ChannelID, nil
+}
+
+// withHome appends --home to args when r.Home is set.
+func (r *Relayer) withHome(args []string) []string {
+	if r.Home == "" {
+		return args
+	}
+	return append(args, "--home", r.Home)
+}
+
+func (r *Relayer) run(ctx context.Context, args ...string) error {
+	cmd := r.newCmd(ctx, r.Binary, r.withHome(args)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, string(out))
+	}
+	return nil
+}
+
+func (r *Relayer) output(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := r.newCmd(ctx, r.Binary, r.withHome(args)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,40 @@
+package relayer
+
+import "testing"
+
+func TestParseChannelState(t *testing.T) {
+	state, err := parseChannelState([]byte(`{"state": "STATE_OPEN"}`))
+	if err != nil {
+		t.Fatalf("parseChannelState: %s", err)
+	}
+	if state != "STATE_OPEN" {
+		t.Fatalf("expected STATE_OPEN, got %q", state)
+	}
+}
+
+func TestParseChannelStateInvalidJSON(t *testing.T) {
+	if _, err := parseChannelState([]byte("not json")); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
+
+func TestParsePathShow(t *testing.T) {
+	out := []byte(`{
+		"src": {"chain-id": "chain-a", "port-id": "transfer", "channel-id": "channel-0"},
+		"dst": {"chain-id": "chain-b", "port-id": "transfer", "channel-id": "channel-1"}
+	}`)
+	port, channel, err := parsePathShow("a-b", out)
+	if err != nil {
+		t.Fatalf("parsePathShow: %s", err)
+	}
+	if port != "transfer" || channel != "channel-0" {
+		t.Fatalf("expected transfer/channel-0, got %s/%s", port, channel)
+	}
+}
+
+func TestParsePathShowNoSrcChannel(t *testing.T) {
+	out := []byte(`{"src": {"chain-id": "chain-a", "port-id": "transfer", "channel-id": ""}}`)
+	if _, _, err := parsePathShow("a-b", out); err == nil {
+		t.Fatal("expected an error when the path has no src channel yet")
+	}
+}
@@ -0,0 +1,332 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SignFunc signs a batch of messages at the given account sequence/number
+// and returns the resulting tx bytes, matching the shape of IbcSign.
+type SignFunc func(ctx context.Context, accSeq, accNum uint64, msgs ...sdktypes.Msg) ([]byte, error)
+
+// BroadcastFunc broadcasts signed tx bytes and returns the chain's response,
+// matching the shape of client.GRPC.BroadcastTx.
+type BroadcastFunc func(ctx context.Context, txBytes []byte) (*sdktypes.TxResponse, error)
+
+// ResyncFunc re-reads the account's sequence/number from the chain, used to
+// recover after a signature/sequence mismatch.
+type ResyncFunc func(ctx context.Context) (accSeq, accNum uint64, err error)
+
+const mempoolFullCode = 0x14
+
+// sdkCodespace, unauthorizedCode and wrongSequenceCode identify the
+// signature-verification and sequence-mismatch rejections that trigger a
+// resync of the account.
+const (
+	sdkCodespace      = "sdk"
+	unauthorizedCode  = 4
+	wrongSequenceCode = 32
+)
+
+// initialPauseBackoff and maxPauseBackoff bound the exponential backoff
+// pause() sleeps through before parking workers on a mempool-full response.
+// The delay doubles on each repeated pause and resets on the next
+// successful broadcast.
+const (
+	initialPauseBackoff = 500 * time.Millisecond
+	maxPauseBackoff     = 8 * time.Second
+)
+
+// job is a single pre-built batch of messages waiting to be signed and
+// broadcast.
+type job struct {
+	msgs []sdktypes.Msg
+}
+
+// Broadcaster owns a pool of worker goroutines that sign and broadcast
+// MsgTransfer batches concurrently. It hands out monotonic account
+// sequence numbers under a mutex so signing can race ahead of broadcasting
+// without producing duplicate or out-of-order sequences.
+type Broadcaster struct {
+	sign      SignFunc
+	broadcast BroadcastFunc
+	resync    ResyncFunc
+
+	jobs chan job
+
+	seqMu  sync.Mutex
+	accSeq uint64
+	accNum uint64
+
+	pauseMu     sync.Mutex
+	paused      bool
+	resume      chan struct{}
+	nextBackoff time.Duration
+
+	sentMu sync.Mutex
+	sent   int
+	failed int
+
+	// blockWg tracks jobs submitted for the block currently in flight, so
+	// Flush can report back once every one of them has actually been
+	// signed and broadcast (or dropped on mempool-full), instead of the
+	// moment they were merely enqueued.
+	blockWg sync.WaitGroup
+
+	wg sync.WaitGroup
+}
+
+// NewBroadcaster returns a Broadcaster with `workers` goroutines and a
+// job queue of depth `inflight`, starting from the given account sequence
+// and number.
+func NewBroadcaster(workers, inflight int, accSeq, accNum uint64, sign SignFunc, broadcast BroadcastFunc, resync ResyncFunc) *Broadcaster {
+	if workers < 1 {
+		workers = 1
+	}
+	if inflight < 1 {
+		inflight = workers
+	}
+	b := &Broadcaster{
+		sign:        sign,
+		broadcast:   broadcast,
+		resync:      resync,
+		jobs:        make(chan job, inflight),
+		accSeq:      accSeq,
+		accNum:      accNum,
+		resume:      make(chan struct{}),
+		nextBackoff: initialPauseBackoff,
+	}
+	for i := 0; i < workers; i++ {
+		b.wg.Add(1)
+		go b.work(context.Background(), i)
+	}
+	return b
+}
+
+// Submit enqueues a batch of messages for signing and broadcast, blocking
+// if the job queue is full. It returns ctx.Err() if ctx is cancelled first.
+func (b *Broadcaster) Submit(ctx context.Context, msgs ...sdktypes.Msg) error {
+	b.blockWg.Add(1)
+	select {
+	case b.jobs <- job{msgs: msgs}:
+		return nil
+	case <-ctx.Done():
+		b.blockWg.Done()
+		return ctx.Err()
+	}
+}
+
+// flushPollInterval bounds how long a worker that re-pauses on mempool-full
+// during Flush can sit unparked, since Flush's caller only calls NextBlock
+// once up front.
+const flushPollInterval = 50 * time.Millisecond
+
+// Flush blocks until every job Submitted so far has been signed and
+// broadcast (or dropped on mempool-full), so callers can read Counts and
+// log an accurate per-block tally before moving on. It respects ctx
+// cancellation even though the underlying WaitGroup does not.
+//
+// Callers invoke NextBlock once before Flush, but a worker can observe
+// mempoolFullCode again and re-pause after that single call returns, with
+// no later NextBlock call reachable until Flush itself returns. Flush keeps
+// calling NextBlock itself while it waits so those workers still drain
+// instead of blocking forever.
+func (b *Broadcaster) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.blockWg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(flushPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			b.NextBlock()
+		}
+	}
+}
+
+// Close stops accepting new jobs and waits for in-flight work to drain. It
+// force-unpauses any worker parked on mempool-full first, since nothing
+// else will ever call NextBlock once the caller is shutting down.
+func (b *Broadcaster) Close() (sent int, failed int) {
+	close(b.jobs)
+	b.NextBlock()
+	b.wg.Wait()
+	return b.sent, b.failed
+}
+
+func (b *Broadcaster) work(ctx context.Context, id int) {
+	defer b.wg.Done()
+	for j := range b.jobs {
+		b.processJob(ctx, id, j)
+	}
+}
+
+func (b *Broadcaster) processJob(ctx context.Context, id int, j job) {
+	defer b.blockWg.Done()
+	b.waitIfPaused()
+
+	seq := b.nextSeq()
+	txByte, err := b.sign(ctx, seq, b.currentAccNum(), j.msgs...)
+	if err != nil {
+		log.Error().Err(err).Int("worker", id).Msg("failed to sign tx")
+		b.resyncAfterError(ctx)
+		b.recordFailed()
+		return
+	}
+
+	resp, err := b.broadcast(ctx, txByte)
+	if err != nil {
+		log.Error().Err(err).Int("worker", id).Msg("failed to broadcast tx")
+		b.resyncAfterError(ctx)
+		b.recordFailed()
+		return
+	}
+
+	if resp.Code == mempoolFullCode {
+		log.Warn().Int("worker", id).Msg("mempool is full, pausing workers until next block")
+		b.rewindSeq(seq)
+		b.recordFailed()
+		b.pause()
+		return
+	}
+	if resp.Code != 0 {
+		log.Warn().Uint32("code", resp.Code).Int("worker", id).Msg("tx rejected")
+		if resp.Codespace == sdkCodespace && (resp.Code == unauthorizedCode || resp.Code == wrongSequenceCode) {
+			b.resyncAfterError(ctx)
+		}
+		b.recordFailed()
+		return
+	}
+	b.recordSent()
+}
+
+func (b *Broadcaster) nextSeq() uint64 {
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+	seq := b.accSeq
+	b.accSeq++
+	return seq
+}
+
+func (b *Broadcaster) rewindSeq(seq uint64) {
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+	if b.accSeq > seq {
+		b.accSeq = seq
+	}
+}
+
+func (b *Broadcaster) currentAccNum() uint64 {
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+	return b.accNum
+}
+
+// resyncAfterError re-reads the account's sequence/number from the chain
+// and rewinds the allocator to match, recovering from signature or
+// sequence-mismatch errors.
+func (b *Broadcaster) resyncAfterError(ctx context.Context) {
+	accSeq, accNum, err := b.resync(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to resync account after error")
+		return
+	}
+	b.seqMu.Lock()
+	b.accSeq = accSeq
+	b.accNum = accNum
+	b.seqMu.Unlock()
+}
+
+// pause sleeps through the current backoff delay, then flips the
+// broadcaster into the paused state so every worker that dequeues a job
+// afterwards blocks in waitIfPaused until NextBlock is called.
+func (b *Broadcaster) pause() {
+	b.pauseMu.Lock()
+	if b.paused {
+		b.pauseMu.Unlock()
+		return
+	}
+	delay := b.nextBackoff
+	b.pauseMu.Unlock()
+
+	time.Sleep(delay)
+
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+	b.paused = true
+	b.nextBackoff *= 2
+	if b.nextBackoff > maxPauseBackoff {
+		b.nextBackoff = maxPauseBackoff
+	}
+}
+
+func (b *Broadcaster) waitIfPaused() {
+	b.pauseMu.Lock()
+	paused := b.paused
+	resume := b.resume
+	b.pauseMu.Unlock()
+	if paused {
+		<-resume
+	}
+}
+
+// NextBlock unblocks any workers paused on a mempool-full response. Callers
+// should invoke this once they observe a new block has been committed.
+func (b *Broadcaster) NextBlock() {
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+	if !b.paused {
+		return
+	}
+	b.paused = false
+	close(b.resume)
+	b.resume = make(chan struct{})
+}
+
+func (b *Broadcaster) recordSent() {
+	b.sentMu.Lock()
+	b.sent++
+	b.sentMu.Unlock()
+	b.resetBackoff()
+}
+
+// resetBackoff drops the mempool-full pause delay back to its initial
+// value, since a successful broadcast means the mempool has room again.
+func (b *Broadcaster) resetBackoff() {
+	b.pauseMu.Lock()
+	b.nextBackoff = initialPauseBackoff
+	b.pauseMu.Unlock()
+}
+
+func (b *Broadcaster) recordFailed() {
+	b.sentMu.Lock()
+	b.failed++
+	b.sentMu.Unlock()
+}
+
+// Counts returns the number of txs sent and failed so far.
+func (b *Broadcaster) Counts() (sent int, failed int) {
+	b.sentMu.Lock()
+	defer b.sentMu.Unlock()
+	return b.sent, b.failed
+}
+
+// String implements fmt.Stringer for debug logging.
+func (b *Broadcaster) String() string {
+	sent, failed := b.Counts()
+	return fmt.Sprintf("Broadcaster{sent=%d, failed=%d}", sent, failed)
+}
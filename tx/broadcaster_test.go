@@ -0,0 +1,134 @@
+package tx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// TestBroadcasterPausesOnMempoolFull exercises the mempool-full pause/resume
+// path: a job that hits mempoolFullCode must rewind its sequence and park
+// every worker until NextBlock is called, and the rewound sequence must be
+// reused once work resumes.
+func TestBroadcasterPausesOnMempoolFull(t *testing.T) {
+	var mu sync.Mutex
+	var seqsSeen []uint64
+
+	sign := func(ctx context.Context, accSeq, accNum uint64, msgs ...sdktypes.Msg) ([]byte, error) {
+		mu.Lock()
+		seqsSeen = append(seqsSeen, accSeq)
+		mu.Unlock()
+		return []byte{byte(accSeq)}, nil
+	}
+
+	var calls int
+	mempoolFull := make(chan struct{})
+	broadcast := func(ctx context.Context, txBytes []byte) (*sdktypes.TxResponse, error) {
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			close(mempoolFull)
+			return &sdktypes.TxResponse{Code: mempoolFullCode}, nil
+		}
+		return &sdktypes.TxResponse{Code: 0}, nil
+	}
+
+	resync := func(ctx context.Context) (uint64, uint64, error) {
+		t.Fatal("resync should not be called for a mempool-full response")
+		return 0, 0, nil
+	}
+
+	b := NewBroadcaster(1, 2, 0, 0, sign, broadcast, resync)
+	ctx := context.Background()
+
+	if err := b.Submit(ctx); err != nil {
+		t.Fatalf("Submit: %s", err)
+	}
+	<-mempoolFull
+
+	// Give pause() time to run its backoff sleep and actually park the
+	// worker before we check that a second job is held back.
+	time.Sleep(initialPauseBackoff + 100*time.Millisecond)
+
+	if err := b.Submit(ctx); err != nil {
+		t.Fatalf("Submit: %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if sent, failed := b.Counts(); sent != 0 || failed != 1 {
+		t.Fatalf("expected the second job to still be parked (sent=0, failed=1), got sent=%d failed=%d", sent, failed)
+	}
+
+	b.NextBlock()
+
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+
+	if sent, failed := b.Counts(); sent != 1 || failed != 1 {
+		t.Fatalf("expected the resumed job to succeed (sent=1, failed=1), got sent=%d failed=%d", sent, failed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seqsSeen) != 2 || seqsSeen[0] != seqsSeen[1] {
+		t.Fatalf("expected the mempool-full job's sequence to be rewound and reused, got %v", seqsSeen)
+	}
+}
+
+// TestFlushDrainsRepeatedMempoolFullPauses exercises a worker that re-pauses
+// on mempool-full after the caller's single up-front NextBlock call: Flush
+// must keep unparking it rather than hang forever.
+func TestFlushDrainsRepeatedMempoolFullPauses(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	broadcast := func(ctx context.Context, txBytes []byte) (*sdktypes.TxResponse, error) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n <= 2 {
+			return &sdktypes.TxResponse{Code: mempoolFullCode}, nil
+		}
+		return &sdktypes.TxResponse{Code: 0}, nil
+	}
+	sign := func(ctx context.Context, accSeq, accNum uint64, msgs ...sdktypes.Msg) ([]byte, error) {
+		return []byte{}, nil
+	}
+	resync := func(ctx context.Context) (uint64, uint64, error) {
+		t.Fatal("resync should not be called for a mempool-full response")
+		return 0, 0, nil
+	}
+
+	b := NewBroadcaster(1, 3, 0, 0, sign, broadcast, resync)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := b.Submit(ctx); err != nil {
+			t.Fatalf("Submit: %s", err)
+		}
+	}
+
+	b.NextBlock()
+
+	flushErr := make(chan error, 1)
+	go func() { flushErr <- b.Flush(ctx) }()
+
+	select {
+	case err := <-flushErr:
+		if err != nil {
+			t.Fatalf("Flush: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush hung on a worker that re-paused after the caller's single NextBlock call")
+	}
+
+	if sent, failed := b.Counts(); sent != 1 || failed != 2 {
+		t.Fatalf("expected sent=1 failed=2, got sent=%d failed=%d", sent, failed)
+	}
+}